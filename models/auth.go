@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Claims are the custom JWT claims issued on login.
+type Claims struct {
+	Username string   `json:"username"`
+	Roles    []string `json:"roles"`
+	jwt.StandardClaims
+}
+
+// JWTOutput is the response returned for a newly issued access token.
+type JWTOutput struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
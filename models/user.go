@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// User is the document stored in the users collection.
+type User struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	Username  string             `json:"username" bson:"username"`
+	Email     string             `json:"email" bson:"email"`
+	Password  string             `json:"password" bson:"password"`
+	Roles     []string           `json:"roles" bson:"roles"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+
+	// Provider and ExternalID identify the OAuth2/OIDC provider a user
+	// signed up through (e.g. "google", "github") and their subject id on
+	// that provider, so a login can be linked back to the account it
+	// auto-provisioned. Empty for users created through SignUp.
+	Provider   string `json:"provider,omitempty" bson:"provider,omitempty"`
+	ExternalID string `json:"external_id,omitempty" bson:"external_id,omitempty"`
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"technical-test/priverion/models"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAuth parses the bearer access token issued by GenerateJWT, validates
+// its signature and expiry, and stores the resulting *models.Claims on the
+// request context under ClaimsContextKey for downstream handlers and
+// middleware (e.g. RequireReauth) to read.
+func RequireAuth() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		tokenString := bearerToken(ctx)
+		if tokenString == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			return
+		}
+
+		claims := &models.Claims{}
+		token, err := jwt.ParseWithClaims(tokenString, claims, func(*jwt.Token) (interface{}, error) {
+			return []byte(os.Getenv("SECRET_KEY")), nil
+		})
+		if err != nil || !token.Valid {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+			return
+		}
+
+		ctx.Set(ClaimsContextKey, claims)
+		ctx.Next()
+	}
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>"
+// header, returning "" if the header is missing or malformed.
+func bearerToken(ctx *gin.Context) string {
+	const prefix = "Bearer "
+	header := ctx.GetHeader("Authorization")
+	if !strings.HasPrefix(header, prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(header, prefix)
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"technical-test/priverion/models"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func signTestToken(t *testing.T, claims *models.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(os.Getenv("SECRET_KEY")))
+	if err != nil {
+		t.Fatalf("could not sign test token: %v", err)
+	}
+	return signed
+}
+
+func newAuthTestRouter() *gin.Engine {
+	router := gin.New()
+	router.GET("/whoami", RequireAuth(), func(ctx *gin.Context) {
+		claims, err := claimsFromContext(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"username": claims.Username})
+	})
+	return router
+}
+
+func TestRequireAuth_ValidTokenSetsClaims(t *testing.T) {
+	os.Setenv("SECRET_KEY", "test-secret")
+	router := newAuthTestRouter()
+
+	token := signTestToken(t, &models.Claims{
+		Username: "alice",
+		Roles:    []string{"user"},
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a valid token, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if !strings.Contains(recorder.Body.String(), "alice") {
+		t.Fatalf("expected response to contain the authenticated username, got: %s", recorder.Body.String())
+	}
+}
+
+func TestRequireAuth_MissingToken(t *testing.T) {
+	router := newAuthTestRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with no Authorization header, got %d", recorder.Code)
+	}
+}
+
+func TestRequireAuth_ExpiredToken(t *testing.T) {
+	os.Setenv("SECRET_KEY", "test-secret")
+	router := newAuthTestRouter()
+
+	token := signTestToken(t, &models.Claims{
+		Username: "alice",
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(-time.Hour).Unix(),
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an expired token, got %d", recorder.Code)
+	}
+}
+
+func TestRequireAuth_WrongSigningKey(t *testing.T) {
+	os.Setenv("SECRET_KEY", "test-secret")
+	router := newAuthTestRouter()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &models.Claims{
+		Username: "alice",
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+	})
+	signed, err := token.SignedString([]byte("not-the-configured-secret"))
+	if err != nil {
+		t.Fatalf("could not sign test token: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.Header.Set("Authorization", "Bearer "+signed)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a token signed with the wrong key, got %d", recorder.Code)
+	}
+}
+
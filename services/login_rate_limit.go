@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/time/rate"
+)
+
+// failureWindow is how long a run of failed attempts is considered
+// continuous; a gap longer than this resets the failure count to 1 instead
+// of accumulating.
+const failureWindow = 15 * time.Minute
+
+// maxFailuresBeforeLock is how many failures within failureWindow trigger a
+// lockout.
+const maxFailuresBeforeLock = 5
+
+// lockoutDurations are the escalating cooldowns applied on each successive
+// lockout for the same account: 1m, 5m, 30m, then 24h for every lockout
+// after that.
+var lockoutDurations = []time.Duration{
+	1 * time.Minute,
+	5 * time.Minute,
+	30 * time.Minute,
+	24 * time.Hour,
+}
+
+// loginAttempt is the per-email document tracking brute-force state.
+type loginAttempt struct {
+	Email         string     `bson:"email"`
+	FailedCount   int        `bson:"failed_count"`
+	LockCount     int        `bson:"lock_count"`
+	LastAttemptAt time.Time  `bson:"last_attempt_at"`
+	LastIP        string     `bson:"last_ip"`
+	LockedUntil   *time.Time `bson:"locked_until"`
+}
+
+// LockedError is returned by LogIn when the account is locked out, carrying
+// enough information for the Gin handler to emit 429 with a Retry-After
+// header.
+type LockedError struct {
+	RetryAfter int       // seconds
+	UnlockAt   time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("account locked, retry after %d seconds", e.RetryAfter)
+}
+
+// IsLocked reports whether email is currently locked out, and if so until
+// when.
+func (us *UserService) IsLocked(email string) (bool, time.Time) {
+	var attempt loginAttempt
+	err := us.loginAttempts.FindOne(context.TODO(), bson.M{"email": email}).Decode(&attempt)
+	if err != nil || attempt.LockedUntil == nil {
+		return false, time.Time{}
+	}
+	if time.Now().After(*attempt.LockedUntil) {
+		return false, time.Time{}
+	}
+	return true, *attempt.LockedUntil
+}
+
+// ResetAttempts clears the failed-attempt counter and any lockout for email,
+// called on every successful login.
+func (us *UserService) ResetAttempts(email string) error {
+	_, err := us.loginAttempts.UpdateOne(context.TODO(),
+		bson.M{"email": email},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "failed_count", Value: 0},
+			{Key: "locked_until", Value: nil},
+		}}},
+	)
+	if err != nil {
+		return fmt.Errorf("could not reset login attempts: %w", err)
+	}
+	return nil
+}
+
+// loginAttemptUpdate is the next state to persist for a loginAttempt
+// document after a failed login, as decided by computeFailedLoginUpdate.
+type loginAttemptUpdate struct {
+	FailedCount int
+	LockCount   int
+	LockedUntil *time.Time
+}
+
+// computeFailedLoginUpdate decides the next failed-attempt state for an
+// account, independent of any database access, so the failure-window reset
+// and lockout-stage escalation can be unit tested without a Mongo
+// connection. hadPrev is false the first time an email fails to log in.
+func computeFailedLoginUpdate(prev loginAttempt, hadPrev bool, now time.Time) (loginAttemptUpdate, *LockedError) {
+	failedCount := 1
+	if hadPrev && now.Sub(prev.LastAttemptAt) <= failureWindow {
+		failedCount = prev.FailedCount + 1
+	}
+
+	if failedCount < maxFailuresBeforeLock {
+		return loginAttemptUpdate{FailedCount: failedCount, LockCount: prev.LockCount}, nil
+	}
+
+	stage := prev.LockCount
+	if stage >= len(lockoutDurations) {
+		stage = len(lockoutDurations) - 1
+	}
+	unlockAt := now.Add(lockoutDurations[stage])
+	return loginAttemptUpdate{
+			FailedCount: 0,
+			LockCount:   prev.LockCount + 1,
+			LockedUntil: &unlockAt,
+		}, &LockedError{
+			RetryAfter: int(time.Until(unlockAt).Seconds()),
+			UnlockAt:   unlockAt,
+		}
+}
+
+// recordFailedLogin registers a failed attempt for email/ip and, once
+// maxFailuresBeforeLock is reached within failureWindow, locks the account
+// for the next escalating cooldown in lockoutDurations.
+//
+// The lockout is keyed on email alone, not (email, ip): ip is recorded as
+// last_ip for audit purposes only. Keying the lockout itself on (email, ip)
+// would let a credential-stuffing attacker spread failed guesses across
+// many source IPs and never trip the per-account lockout at all, which is
+// the exact attack this is meant to blunt. LoginRateLimiter below separately
+// caps the request rate of each single source IP.
+func (us *UserService) recordFailedLogin(email, ip string) (*LockedError, error) {
+	var attempt loginAttempt
+	err := us.loginAttempts.FindOne(context.TODO(), bson.M{"email": email}).Decode(&attempt)
+	hadPrev := true
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+		hadPrev = false
+	}
+
+	now := time.Now()
+	next, lockedErr := computeFailedLoginUpdate(attempt, hadPrev, now)
+
+	set := bson.M{
+		"email":           email,
+		"failed_count":    next.FailedCount,
+		"lock_count":      next.LockCount,
+		"last_attempt_at": now,
+		"last_ip":         ip,
+		"locked_until":    nil,
+	}
+	if next.LockedUntil != nil {
+		set["locked_until"] = *next.LockedUntil
+	}
+
+	if _, err := us.loginAttempts.UpdateOne(context.TODO(),
+		bson.M{"email": email},
+		bson.D{{Key: "$set", Value: set}},
+		options.Update().SetUpsert(true),
+	); err != nil {
+		return nil, err
+	}
+	return lockedErr, nil
+}
+
+// ipLimiterTTL is how long an IP's limiter may sit idle before it is
+// evicted. Without this, an attacker (or just organic traffic) touching
+// many distinct source IPs would grow the limiter map without bound.
+const ipLimiterTTL = 10 * time.Minute
+
+// ipLimiterEntry pairs a token-bucket limiter with when it was last used, so
+// gcLocked can evict entries that have gone idle.
+type ipLimiterEntry struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// ipLimiters holds one token-bucket limiter per source IP for LoginRateLimiter,
+// evicting entries that have been idle for longer than ttl.
+type ipLimiters struct {
+	mu       sync.Mutex
+	limiters map[string]*ipLimiterEntry
+	r        rate.Limit
+	burst    int
+	ttl      time.Duration
+	lastGC   time.Time
+}
+
+func newIPLimiters(r rate.Limit, burst int) *ipLimiters {
+	return &ipLimiters{
+		limiters: make(map[string]*ipLimiterEntry),
+		r:        r,
+		burst:    burst,
+		ttl:      ipLimiterTTL,
+	}
+}
+
+func (l *ipLimiters) get(ip string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastGC) > l.ttl {
+		l.gcLocked(now)
+	}
+
+	entry, ok := l.limiters[ip]
+	if !ok {
+		entry = &ipLimiterEntry{limiter: rate.NewLimiter(l.r, l.burst)}
+		l.limiters[ip] = entry
+	}
+	entry.lastUsed = now
+	return entry.limiter
+}
+
+// gcLocked drops limiters that have been idle longer than l.ttl. Callers
+// must hold l.mu.
+func (l *ipLimiters) gcLocked(now time.Time) {
+	for ip, entry := range l.limiters {
+		if now.Sub(entry.lastUsed) > l.ttl {
+			delete(l.limiters, ip)
+		}
+	}
+	l.lastGC = now
+}
+
+// loginLimiters blunts credential-stuffing by capping each source IP to a
+// small number of signup/login attempts per second, independent of the
+// per-account lockout above.
+var loginLimiters = newIPLimiters(rate.Every(time.Second), 5)
+
+// LoginRateLimiter is Gin middleware meant to front /signup and /login: it
+// token-bucket limits requests per source IP, returning 429 once exhausted.
+func LoginRateLimiter() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !loginLimiters.get(ctx.ClientIP()).Allow() {
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "too many requests, slow down",
+			})
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestIPLimiters_EvictsIdleEntries(t *testing.T) {
+	limiters := newIPLimiters(rate.Every(time.Second), 5)
+	limiters.ttl = time.Minute
+
+	limiters.get("1.2.3.4")
+	if len(limiters.limiters) != 1 {
+		t.Fatalf("expected 1 tracked IP, got %d", len(limiters.limiters))
+	}
+
+	// Simulate the entry having gone idle past the TTL, then force a sweep.
+	limiters.limiters["1.2.3.4"].lastUsed = time.Now().Add(-2 * time.Minute)
+	limiters.gcLocked(time.Now())
+
+	if len(limiters.limiters) != 0 {
+		t.Fatalf("expected idle entry to be evicted, still tracking %d", len(limiters.limiters))
+	}
+}
+
+func TestIPLimiters_KeepsRecentlyUsedEntries(t *testing.T) {
+	limiters := newIPLimiters(rate.Every(time.Second), 5)
+	limiters.ttl = time.Minute
+
+	limiters.get("1.2.3.4")
+	limiters.gcLocked(time.Now())
+
+	if len(limiters.limiters) != 1 {
+		t.Fatal("gcLocked evicted an entry that was still within its TTL")
+	}
+}
+
+func TestComputeFailedLoginUpdate_AccumulatesBelowThreshold(t *testing.T) {
+	now := time.Now()
+	prev := loginAttempt{FailedCount: 2, LastAttemptAt: now.Add(-time.Minute)}
+
+	next, lockedErr := computeFailedLoginUpdate(prev, true, now)
+
+	if lockedErr != nil {
+		t.Fatalf("expected no lockout below maxFailuresBeforeLock, got %v", lockedErr)
+	}
+	if next.FailedCount != 3 {
+		t.Fatalf("expected failed count to accumulate to 3, got %d", next.FailedCount)
+	}
+}
+
+func TestComputeFailedLoginUpdate_NoPriorAttemptStartsAtOne(t *testing.T) {
+	next, lockedErr := computeFailedLoginUpdate(loginAttempt{}, false, time.Now())
+
+	if lockedErr != nil {
+		t.Fatalf("expected no lockout on a first-ever failure, got %v", lockedErr)
+	}
+	if next.FailedCount != 1 {
+		t.Fatalf("expected failed count 1 for a first failure, got %d", next.FailedCount)
+	}
+}
+
+func TestComputeFailedLoginUpdate_ResetsAfterFailureWindowElapses(t *testing.T) {
+	now := time.Now()
+	prev := loginAttempt{FailedCount: 4, LastAttemptAt: now.Add(-(failureWindow + time.Minute))}
+
+	next, lockedErr := computeFailedLoginUpdate(prev, true, now)
+
+	if lockedErr != nil {
+		t.Fatalf("expected no lockout after the failure window reset the count, got %v", lockedErr)
+	}
+	if next.FailedCount != 1 {
+		t.Fatalf("expected a gap longer than failureWindow to reset failed count to 1, got %d", next.FailedCount)
+	}
+}
+
+func TestComputeFailedLoginUpdate_LocksOnReachingThreshold(t *testing.T) {
+	now := time.Now()
+	prev := loginAttempt{FailedCount: maxFailuresBeforeLock - 1, LastAttemptAt: now.Add(-time.Minute)}
+
+	next, lockedErr := computeFailedLoginUpdate(prev, true, now)
+
+	if lockedErr == nil {
+		t.Fatal("expected reaching maxFailuresBeforeLock to lock the account")
+	}
+	if next.FailedCount != 0 {
+		t.Fatalf("expected failed count to reset to 0 once locked, got %d", next.FailedCount)
+	}
+	if next.LockCount != 1 {
+		t.Fatalf("expected lock count to advance to 1 on first lockout, got %d", next.LockCount)
+	}
+	if next.LockedUntil == nil || !next.LockedUntil.After(now) {
+		t.Fatal("expected LockedUntil to be set in the future")
+	}
+	wantDuration := lockoutDurations[0]
+	if gotDuration := next.LockedUntil.Sub(now); gotDuration < wantDuration-time.Second || gotDuration > wantDuration+time.Second {
+		t.Fatalf("expected first lockout to use %v, got %v", wantDuration, gotDuration)
+	}
+}
+
+func TestComputeFailedLoginUpdate_EscalatesThroughStages(t *testing.T) {
+	now := time.Now()
+
+	for stage, wantDuration := range lockoutDurations {
+		prev := loginAttempt{
+			FailedCount:   maxFailuresBeforeLock - 1,
+			LockCount:     stage,
+			LastAttemptAt: now.Add(-time.Minute),
+		}
+
+		next, lockedErr := computeFailedLoginUpdate(prev, true, now)
+
+		if lockedErr == nil {
+			t.Fatalf("stage %d: expected a lockout", stage)
+		}
+		if next.LockCount != stage+1 {
+			t.Fatalf("stage %d: expected lock count %d, got %d", stage, stage+1, next.LockCount)
+		}
+		if gotDuration := next.LockedUntil.Sub(now); gotDuration < wantDuration-time.Second || gotDuration > wantDuration+time.Second {
+			t.Fatalf("stage %d: expected duration %v, got %v", stage, wantDuration, gotDuration)
+		}
+	}
+}
+
+func TestComputeFailedLoginUpdate_CapsAtLastStageBeyondConfiguredDurations(t *testing.T) {
+	now := time.Now()
+	lastStage := len(lockoutDurations) - 1
+	prev := loginAttempt{
+		FailedCount:   maxFailuresBeforeLock - 1,
+		LockCount:     lastStage + 5,
+		LastAttemptAt: now.Add(-time.Minute),
+	}
+
+	next, lockedErr := computeFailedLoginUpdate(prev, true, now)
+
+	if lockedErr == nil {
+		t.Fatal("expected a lockout")
+	}
+	wantDuration := lockoutDurations[lastStage]
+	if gotDuration := next.LockedUntil.Sub(now); gotDuration < wantDuration-time.Second || gotDuration > wantDuration+time.Second {
+		t.Fatalf("expected lockout beyond the configured stages to cap at %v, got %v", wantDuration, gotDuration)
+	}
+}
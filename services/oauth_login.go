@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"technical-test/priverion/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RegisterProvider wires a named AuthProvider (e.g. "google", "github",
+// "oidc") into the service after initializing it with cfg.
+func (us *UserService) RegisterProvider(name string, provider AuthProvider, cfg OAuthProviderConfig) error {
+	if err := provider.InitProvider(cfg); err != nil {
+		return fmt.Errorf("could not init %s provider: %w", name, err)
+	}
+	us.providers[name] = provider
+	return nil
+}
+
+// SetStateStore swaps the default in-memory CSRF state store for another
+// implementation (e.g. a Mongo-backed one shared across instances).
+func (us *UserService) SetStateStore(store StateStore) {
+	us.stateStore = store
+}
+
+// SetDefaultOAuthRoles configures the roles assigned to users auto-provisioned
+// through an OAuth2/OIDC callback.
+func (us *UserService) SetDefaultOAuthRoles(roles []string) {
+	us.defaultOAuthRoles = roles
+}
+
+// HasAdmin reports whether any user in the collection already holds the
+// "admin" role. It is used to promote the very first account created through
+// any login provider to admin.
+func (us *UserService) HasAdmin() (bool, error) {
+	count, err := us.collection.CountDocuments(context.TODO(), bson.M{"roles": "admin"})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// HandleOAuthLogin redirects the caller to providerName's consent screen,
+// stamping a one-time CSRF state nonce that HandleOAuthCallback verifies.
+func (us *UserService) HandleOAuthLogin(ctx *gin.Context, providerName string) error {
+	provider, ok := us.providers[providerName]
+	if !ok {
+		return fmt.Errorf("unknown auth provider: %s", providerName)
+	}
+
+	state, err := us.stateStore.Generate(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx.Redirect(http.StatusTemporaryRedirect, provider.HandleLogin(ctx, state))
+	return nil
+}
+
+// HandleOAuthCallback completes the authorization-code flow for providerName:
+// it verifies the CSRF state, exchanges the code, resolves the provider
+// identity, auto-provisions a models.User on first login (linking by verified
+// email), and returns the same token pair LogIn returns.
+func (us *UserService) HandleOAuthCallback(ctx *gin.Context, providerName string) (TokenPair, error) {
+	provider, ok := us.providers[providerName]
+	if !ok {
+		return TokenPair{}, fmt.Errorf("unknown auth provider: %s", providerName)
+	}
+
+	state := ctx.Query("state")
+	valid, err := us.stateStore.Consume(ctx, state)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if !valid {
+		return TokenPair{}, fmt.Errorf("invalid or expired oauth state")
+	}
+
+	code := ctx.Query("code")
+	token, err := provider.HandleCallback(ctx, code)
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("could not exchange authorization code: %w", err)
+	}
+
+	identity, err := provider.VerifyToken(ctx, token)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if !identity.EmailVerified || identity.Email == "" {
+		return TokenPair{}, fmt.Errorf("provider did not return a verified email")
+	}
+
+	foundUser, err := us.findOrProvisionOAuthUser(providerName, identity)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	access, err := us.GenerateJWT(foundUser)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	refresh, _, refreshExpiresAt, err := us.issueRefreshToken(ctx, foundUser.ID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		Access:         access,
+		RefreshToken:   refresh,
+		RefreshExpires: refreshExpiresAt,
+	}, nil
+}
+
+// findOrProvisionOAuthUser links to an existing user by verified email, or
+// auto-provisions a new one on first callback. The very first user ever
+// created (via any provider) is bootstrapped as admin.
+func (us *UserService) findOrProvisionOAuthUser(providerName string, identity ProviderIdentity) (models.User, error) {
+	var foundUser models.User
+	err := us.collection.FindOne(context.TODO(), bson.M{"email": identity.Email}).Decode(&foundUser)
+	if err == nil {
+		return foundUser, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return models.User{}, err
+	}
+
+	roles := append([]string{}, us.defaultOAuthRoles...)
+	isFirstAdmin, err := us.claimFirstAdmin()
+	if err != nil {
+		return models.User{}, err
+	}
+	if isFirstAdmin {
+		roles = append(roles, "admin")
+	}
+
+	newUser := models.User{
+		ID:         primitive.NewObjectID(),
+		Username:   identity.Name,
+		Email:      identity.Email,
+		Roles:      roles,
+		CreatedAt:  time.Now(),
+		Provider:   providerName,
+		ExternalID: identity.Subject,
+	}
+	if _, err := us.collection.InsertOne(context.TODO(), newUser); err != nil {
+		return models.User{}, err
+	}
+	return newUser, nil
+}
+
+// bootstrapAdminMarker is the fixed _id of a sentinel document used to claim
+// the "first user becomes admin" bootstrap atomically: Mongo guarantees at
+// most one InsertOne with a given _id succeeds, so concurrent first-time
+// OAuth callbacks can't both win HasAdmin's read-then-write race and both be
+// promoted to admin.
+var bootstrapAdminMarker = bson.M{"_id": "oauth_admin_bootstrap_claimed"}
+
+// claimFirstAdmin atomically claims the one-time "promote to admin" slot.
+// It returns true only for the single caller that wins the race to insert
+// the sentinel document; every other (concurrent or later) caller gets
+// false, whether or not an admin already existed from SignUp or a prior
+// callback.
+func (us *UserService) claimFirstAdmin() (bool, error) {
+	hasAdmin, err := us.HasAdmin()
+	if err != nil {
+		return false, err
+	}
+	if hasAdmin {
+		return false, nil
+	}
+
+	_, err = us.oauthBootstrap.InsertOne(context.TODO(), bootstrapAdminMarker)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+	"golang.org/x/oauth2/google"
+)
+
+// OAuthProviderConfig holds the pieces every AuthProvider needs to perform
+// the authorization-code flow. Generic OIDC providers additionally rely on
+// UserInfoURL since this package does not do OIDC discovery.
+type OAuthProviderConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	Endpoint     oauth2.Endpoint // only used by the generic OIDC provider
+	UserInfoURL  string          // only used by the generic OIDC provider
+}
+
+// ProviderIdentity is the normalized user info returned by a provider after
+// the callback has exchanged the authorization code for a token.
+type ProviderIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// AuthProvider is implemented by every pluggable OAuth2/OIDC login provider
+// (Google, GitHub, generic OIDC, ...). InitProvider wires up the oauth2
+// config, HandleLogin returns the URL of the provider's consent screen,
+// HandleCallback exchanges the authorization code for a token, and
+// VerifyToken resolves an access token back to a ProviderIdentity.
+type AuthProvider interface {
+	Name() string
+	InitProvider(cfg OAuthProviderConfig) error
+	HandleLogin(ctx context.Context, state string) (redirectURL string)
+	HandleCallback(ctx context.Context, code string) (*oauth2.Token, error)
+	VerifyToken(ctx context.Context, token *oauth2.Token) (ProviderIdentity, error)
+}
+
+// StateStore generates and consumes one-time CSRF state nonces for the
+// OAuth2 authorization-code flow. Consume must be single-use: once a state
+// has been returned it may never be returned again.
+type StateStore interface {
+	Generate(ctx context.Context) (string, error)
+	Consume(ctx context.Context, state string) (bool, error)
+}
+
+// memoryStateStore is a TTL-based, single-process StateStore. It is the
+// default wired up by NewUserService; swap in a Mongo-backed StateStore via
+// SetStateStore for multi-instance deployments.
+type memoryStateStore struct {
+	mu     sync.Mutex
+	states map[string]time.Time
+	ttl    time.Duration
+}
+
+func newMemoryStateStore(ttl time.Duration) *memoryStateStore {
+	return &memoryStateStore{
+		states: make(map[string]time.Time),
+		ttl:    ttl,
+	}
+}
+
+func (s *memoryStateStore) Generate(ctx context.Context) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	state := hex.EncodeToString(raw)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gcLocked()
+	s.states[state] = time.Now().Add(s.ttl)
+	return state, nil
+}
+
+func (s *memoryStateStore) Consume(ctx context.Context, state string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiresAt, ok := s.states[state]
+	if !ok {
+		return false, nil
+	}
+	delete(s.states, state)
+	return time.Now().Before(expiresAt), nil
+}
+
+// gcLocked drops expired states. Callers must hold s.mu.
+func (s *memoryStateStore) gcLocked() {
+	now := time.Now()
+	for state, expiresAt := range s.states {
+		if now.After(expiresAt) {
+			delete(s.states, state)
+		}
+	}
+}
+
+// googleProvider implements AuthProvider for Google Sign-In.
+type googleProvider struct {
+	oauthConfig *oauth2.Config
+}
+
+func (p *googleProvider) Name() string { return "google" }
+
+func (p *googleProvider) InitProvider(cfg OAuthProviderConfig) error {
+	p.oauthConfig = &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       defaultScopes(cfg.Scopes, "openid", "email", "profile"),
+		Endpoint:     google.Endpoint,
+	}
+	return nil
+}
+
+func (p *googleProvider) HandleLogin(ctx context.Context, state string) string {
+	return p.oauthConfig.AuthCodeURL(state)
+}
+
+func (p *googleProvider) HandleCallback(ctx context.Context, code string) (*oauth2.Token, error) {
+	return p.oauthConfig.Exchange(ctx, code)
+}
+
+func (p *googleProvider) VerifyToken(ctx context.Context, token *oauth2.Token) (ProviderIdentity, error) {
+	return fetchUserInfo(ctx, p.oauthConfig, token, "https://openidconnect.googleapis.com/v1/userinfo")
+}
+
+// githubProvider implements AuthProvider for GitHub OAuth apps.
+type githubProvider struct {
+	oauthConfig *oauth2.Config
+}
+
+func (p *githubProvider) Name() string { return "github" }
+
+func (p *githubProvider) InitProvider(cfg OAuthProviderConfig) error {
+	p.oauthConfig = &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       defaultScopes(cfg.Scopes, "read:user", "user:email"),
+		Endpoint:     github.Endpoint,
+	}
+	return nil
+}
+
+func (p *githubProvider) HandleLogin(ctx context.Context, state string) string {
+	return p.oauthConfig.AuthCodeURL(state)
+}
+
+func (p *githubProvider) HandleCallback(ctx context.Context, code string) (*oauth2.Token, error) {
+	return p.oauthConfig.Exchange(ctx, code)
+}
+
+func (p *githubProvider) VerifyToken(ctx context.Context, token *oauth2.Token) (ProviderIdentity, error) {
+	identity, err := fetchUserInfo(ctx, p.oauthConfig, token, "https://api.github.com/user")
+	if err != nil {
+		return ProviderIdentity{}, err
+	}
+
+	// GitHub's /user endpoint doesn't report verification status, and its
+	// "email" field can be unset or unverified. Only trust an email surfaced
+	// by /user/emails as the account's verified primary address.
+	email, verified, err := githubPrimaryVerifiedEmail(ctx, p.oauthConfig, token)
+	if err != nil {
+		return ProviderIdentity{}, err
+	}
+	identity.Email = email
+	identity.EmailVerified = verified
+	return identity, nil
+}
+
+// githubPrimaryVerifiedEmail calls GitHub's /user/emails endpoint (requires
+// the user:email scope) and returns the address marked both primary and
+// verified, if any.
+func githubPrimaryVerifiedEmail(ctx context.Context, cfg *oauth2.Config, token *oauth2.Token) (string, bool, error) {
+	client := cfg.Client(ctx, token)
+	resp, err := client.Get("https://api.github.com/user/emails")
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", false, fmt.Errorf("user/emails request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&emails); err != nil {
+		return "", false, err
+	}
+
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			return e.Email, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// oidcProvider implements AuthProvider for any generic OpenID Connect
+// provider configured with an explicit authorization/token endpoint and
+// userinfo URL (no discovery document is fetched).
+type oidcProvider struct {
+	oauthConfig *oauth2.Config
+	userInfoURL string
+}
+
+func (p *oidcProvider) Name() string { return "oidc" }
+
+func (p *oidcProvider) InitProvider(cfg OAuthProviderConfig) error {
+	if cfg.UserInfoURL == "" {
+		return fmt.Errorf("oidc provider requires UserInfoURL")
+	}
+	p.oauthConfig = &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       defaultScopes(cfg.Scopes, "openid", "email", "profile"),
+		Endpoint:     cfg.Endpoint,
+	}
+	p.userInfoURL = cfg.UserInfoURL
+	return nil
+}
+
+func (p *oidcProvider) HandleLogin(ctx context.Context, state string) string {
+	return p.oauthConfig.AuthCodeURL(state)
+}
+
+func (p *oidcProvider) HandleCallback(ctx context.Context, code string) (*oauth2.Token, error) {
+	return p.oauthConfig.Exchange(ctx, code)
+}
+
+func (p *oidcProvider) VerifyToken(ctx context.Context, token *oauth2.Token) (ProviderIdentity, error) {
+	return fetchUserInfo(ctx, p.oauthConfig, token, p.userInfoURL)
+}
+
+func defaultScopes(configured []string, fallback ...string) []string {
+	if len(configured) > 0 {
+		return configured
+	}
+	return fallback
+}
+
+// fetchUserInfo calls a provider's userinfo endpoint with the given token and
+// normalizes the (loosely-typed) JSON response into a ProviderIdentity.
+func fetchUserInfo(ctx context.Context, cfg *oauth2.Config, token *oauth2.Token, userInfoURL string) (ProviderIdentity, error) {
+	client := cfg.Client(ctx, token)
+	resp, err := client.Get(userInfoURL)
+	if err != nil {
+		return ProviderIdentity{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return ProviderIdentity{}, fmt.Errorf("userinfo request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var raw struct {
+		Sub           string `json:"sub"`
+		ID            int64  `json:"id"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+		Login         string `json:"login"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return ProviderIdentity{}, err
+	}
+
+	subject := raw.Sub
+	if subject == "" && raw.ID != 0 {
+		subject = fmt.Sprintf("%d", raw.ID)
+	}
+	name := raw.Name
+	if name == "" {
+		name = raw.Login
+	}
+
+	return ProviderIdentity{
+		Subject:       subject,
+		Email:         raw.Email,
+		EmailVerified: raw.EmailVerified,
+		Name:          name,
+	}, nil
+}
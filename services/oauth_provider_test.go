@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStateStore_ConsumeIsSingleUse(t *testing.T) {
+	store := newMemoryStateStore(time.Minute)
+
+	state, err := store.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+
+	valid, err := store.Consume(context.Background(), state)
+	if err != nil {
+		t.Fatalf("Consume returned error: %v", err)
+	}
+	if !valid {
+		t.Fatal("expected the freshly generated state to be valid")
+	}
+
+	valid, err = store.Consume(context.Background(), state)
+	if err != nil {
+		t.Fatalf("Consume returned error: %v", err)
+	}
+	if valid {
+		t.Fatal("expected a second Consume of the same state to fail (replay)")
+	}
+}
+
+func TestMemoryStateStore_UnknownStateRejected(t *testing.T) {
+	store := newMemoryStateStore(time.Minute)
+
+	valid, err := store.Consume(context.Background(), "never-generated")
+	if err != nil {
+		t.Fatalf("Consume returned error: %v", err)
+	}
+	if valid {
+		t.Fatal("expected an unknown state to be rejected")
+	}
+}
+
+func TestMemoryStateStore_ExpiredStateRejected(t *testing.T) {
+	store := newMemoryStateStore(time.Minute)
+
+	state, err := store.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+
+	// Simulate the TTL having elapsed without sleeping for real.
+	store.mu.Lock()
+	store.states[state] = time.Now().Add(-time.Second)
+	store.mu.Unlock()
+
+	valid, err := store.Consume(context.Background(), state)
+	if err != nil {
+		t.Fatalf("Consume returned error: %v", err)
+	}
+	if valid {
+		t.Fatal("expected an expired state to be rejected")
+	}
+}
+
+func TestMemoryStateStore_StatesAreUnique(t *testing.T) {
+	store := newMemoryStateStore(time.Minute)
+
+	a, err := store.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	b, err := store.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+
+	if a == b {
+		t.Fatal("Generate produced the same state twice")
+	}
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHasher hashes and verifies passwords behind a single interface so
+// the algorithm and its cost parameters can change without touching callers.
+// Verify reports needsRehash when the stored hash was produced with an older
+// algorithm or weaker parameters than the hasher is currently configured
+// with, so LogIn can transparently upgrade it.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(password, encoded string) (ok bool, needsRehash bool, err error)
+}
+
+// bcryptHasher is the pre-existing hashing scheme, kept around so hashes
+// created before the Argon2id migration keep verifying.
+type bcryptHasher struct {
+	cost int
+}
+
+func newBcryptHasher(cost int) *bcryptHasher {
+	return &bcryptHasher{cost: cost}
+}
+
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (h *bcryptHasher) Verify(password, encoded string) (bool, bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err != nil {
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return true, true, nil
+	}
+	return true, cost != h.cost, nil
+}
+
+// argon2Params are the cost parameters encoded alongside an Argon2id hash.
+type argon2Params struct {
+	memory      uint32
+	time        uint32
+	parallelism uint8
+	saltLength  uint32
+	keyLength   uint32
+}
+
+// argon2idHasher hashes passwords with Argon2id, encoding the parameters used
+// into the hash itself (`$argon2id$v=19$m=...,t=...,p=...$salt$hash`) so a
+// later change in defaults doesn't break verification of older hashes.
+type argon2idHasher struct {
+	params argon2Params
+}
+
+func newArgon2idHasher(memory, time uint32, parallelism uint8, saltLength, keyLength uint32) *argon2idHasher {
+	return &argon2idHasher{params: argon2Params{
+		memory:      memory,
+		time:        time,
+		parallelism: parallelism,
+		saltLength:  saltLength,
+		keyLength:   keyLength,
+	}}
+}
+
+func (h *argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	hash := argon2.IDKey([]byte(password), salt, h.params.time, h.params.memory, h.params.parallelism, h.params.keyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.params.memory, h.params.time, h.params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	), nil
+}
+
+func (h *argon2idHasher) Verify(password, encoded string) (bool, bool, error) {
+	params, salt, hash, err := decodeArgon2idHash(encoded)
+	if err != nil {
+		return false, false, err
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.parallelism, uint32(len(hash)))
+	if subtle.ConstantTimeCompare(hash, computed) != 1 {
+		return false, false, nil
+	}
+
+	needsRehash := params != h.params
+	return true, needsRehash, nil
+}
+
+func decodeArgon2idHash(encoded string) (argon2Params, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return argon2Params{}, nil, nil, fmt.Errorf("malformed argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return argon2Params{}, nil, nil, fmt.Errorf("malformed argon2id version: %w", err)
+	}
+	if version != argon2.Version {
+		return argon2Params{}, nil, nil, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+
+	var params argon2Params
+	var parallelism int
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &parallelism); err != nil {
+		return argon2Params{}, nil, nil, fmt.Errorf("malformed argon2id params: %w", err)
+	}
+	params.parallelism = uint8(parallelism)
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return argon2Params{}, nil, nil, fmt.Errorf("malformed argon2id salt: %w", err)
+	}
+	params.saltLength = uint32(len(salt))
+
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return argon2Params{}, nil, nil, fmt.Errorf("malformed argon2id hash: %w", err)
+	}
+	params.keyLength = uint32(len(hash))
+
+	return params, salt, hash, nil
+}
+
+// compositeHasher dispatches Verify to the right algorithm by inspecting the
+// encoded hash's prefix, while always Hash-ing new passwords with current.
+// This is what lets the configured algorithm change (or its cost parameters
+// tighten) with zero downtime: old hashes keep verifying and get upgraded to
+// current on next successful login.
+type compositeHasher struct {
+	current       PasswordHasher
+	currentPrefix string
+	byPrefix      map[string]PasswordHasher
+}
+
+func newCompositeHasher(current PasswordHasher, currentPrefix string, byPrefix map[string]PasswordHasher) *compositeHasher {
+	return &compositeHasher{current: current, currentPrefix: currentPrefix, byPrefix: byPrefix}
+}
+
+func (h *compositeHasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+func (h *compositeHasher) Verify(password, encoded string) (bool, bool, error) {
+	prefix := algorithmPrefix(encoded)
+	hasher, ok := h.byPrefix[prefix]
+	if !ok {
+		return false, false, fmt.Errorf("unrecognized password hash algorithm")
+	}
+
+	ok, needsRehash, err := hasher.Verify(password, encoded)
+	if err != nil || !ok {
+		return ok, needsRehash, err
+	}
+
+	if prefix != h.currentPrefix {
+		needsRehash = true
+	}
+	return true, needsRehash, nil
+}
+
+func algorithmPrefix(encoded string) string {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return "$argon2id$"
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return "$2$"
+	default:
+		return ""
+	}
+}
+
+// defaultBcryptCost matches the cost factor the password hashing used before
+// the Argon2id migration.
+const defaultBcryptCost = 14
+
+// passwordHasher is the package-wide hasher used by hashPassword and
+// verifyPassword. Argon2id is the current algorithm; bcrypt hashes created
+// before this migration keep verifying and are rehashed to Argon2id the next
+// time their owner logs in successfully.
+var passwordHasher PasswordHasher = newCompositeHasher(
+	newArgon2idHasher(64*1024, 3, 2, 16, 32),
+	"$argon2id$",
+	map[string]PasswordHasher{
+		"$argon2id$": newArgon2idHasher(64*1024, 3, 2, 16, 32),
+		"$2$":        newBcryptHasher(defaultBcryptCost),
+	},
+)
@@ -0,0 +1,147 @@
+package services
+
+import "testing"
+
+func TestArgon2idHasher_HashVerifyRoundTrip(t *testing.T) {
+	hasher := newArgon2idHasher(64*1024, 3, 2, 16, 32)
+
+	encoded, err := hasher.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Hash returned error: %v", err)
+	}
+
+	ok, needsRehash, err := hasher.Verify("correct horse battery staple", encoded)
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if !ok {
+		t.Fatal("Verify did not accept the password it just hashed")
+	}
+	if needsRehash {
+		t.Fatal("Verify flagged needsRehash for a hash produced with the current params")
+	}
+
+	ok, _, err = hasher.Verify("wrong password", encoded)
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if ok {
+		t.Fatal("Verify accepted an incorrect password")
+	}
+}
+
+func TestArgon2idHasher_NeedsRehashOnParamChange(t *testing.T) {
+	old := newArgon2idHasher(32*1024, 1, 1, 16, 32)
+	encoded, err := old.Hash("hunter2")
+	if err != nil {
+		t.Fatalf("Hash returned error: %v", err)
+	}
+
+	upgraded := newArgon2idHasher(64*1024, 3, 2, 16, 32)
+	ok, needsRehash, err := upgraded.Verify("hunter2", encoded)
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if !ok {
+		t.Fatal("Verify rejected a password hashed with older (but still matching) params")
+	}
+	if !needsRehash {
+		t.Fatal("Verify did not flag needsRehash when stored params are weaker than configured")
+	}
+}
+
+func TestBcryptHasher_HashVerifyRoundTrip(t *testing.T) {
+	hasher := newBcryptHasher(4) // low cost keeps the test fast
+
+	encoded, err := hasher.Hash("hunter2")
+	if err != nil {
+		t.Fatalf("Hash returned error: %v", err)
+	}
+
+	ok, needsRehash, err := hasher.Verify("hunter2", encoded)
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if !ok {
+		t.Fatal("Verify did not accept the password it just hashed")
+	}
+	if needsRehash {
+		t.Fatal("Verify flagged needsRehash for a hash produced with the current cost")
+	}
+}
+
+func TestBcryptHasher_NeedsRehashOnCostChange(t *testing.T) {
+	old := newBcryptHasher(4)
+	encoded, err := old.Hash("hunter2")
+	if err != nil {
+		t.Fatalf("Hash returned error: %v", err)
+	}
+
+	upgraded := newBcryptHasher(6)
+	ok, needsRehash, err := upgraded.Verify("hunter2", encoded)
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if !ok {
+		t.Fatal("Verify rejected a password hashed at a lower cost")
+	}
+	if !needsRehash {
+		t.Fatal("Verify did not flag needsRehash when the configured cost has changed")
+	}
+}
+
+func TestCompositeHasher_DispatchesByPrefix(t *testing.T) {
+	bcryptH := newBcryptHasher(4)
+	argonH := newArgon2idHasher(32*1024, 1, 1, 16, 32)
+	composite := newCompositeHasher(argonH, "$argon2id$", map[string]PasswordHasher{
+		"$argon2id$": argonH,
+		"$2$":        bcryptH,
+	})
+
+	bcryptEncoded, err := bcryptH.Hash("legacy-password")
+	if err != nil {
+		t.Fatalf("bcrypt Hash returned error: %v", err)
+	}
+	ok, needsRehash, err := composite.Verify("legacy-password", bcryptEncoded)
+	if err != nil {
+		t.Fatalf("Verify returned error for bcrypt hash: %v", err)
+	}
+	if !ok {
+		t.Fatal("composite hasher rejected a valid bcrypt hash")
+	}
+	if !needsRehash {
+		t.Fatal("composite hasher should flag needsRehash for a non-current algorithm")
+	}
+
+	argonEncoded, err := composite.Hash("new-password")
+	if err != nil {
+		t.Fatalf("composite Hash returned error: %v", err)
+	}
+	if algorithmPrefix(argonEncoded) != "$argon2id$" {
+		t.Fatalf("composite Hash did not use the current algorithm, got %q", argonEncoded)
+	}
+	ok, needsRehash, err = composite.Verify("new-password", argonEncoded)
+	if err != nil {
+		t.Fatalf("Verify returned error for argon2id hash: %v", err)
+	}
+	if !ok {
+		t.Fatal("composite hasher rejected a valid argon2id hash")
+	}
+	if needsRehash {
+		t.Fatal("composite hasher flagged needsRehash for a hash already using the current algorithm")
+	}
+}
+
+func TestDecodeArgon2idHash_RejectsMalformedInput(t *testing.T) {
+	cases := []string{
+		"",
+		"not-an-argon2-hash",
+		"$argon2id$v=19$m=65536,t=3,p=2$onlyfourfields",
+		"$argon2id$v=1$m=65536,t=3,p=2$c2FsdA$aGFzaA", // wrong version
+	}
+	for _, encoded := range cases {
+		if _, _, _, err := decodeArgon2idHash(encoded); err == nil {
+			t.Errorf("decodeArgon2idHash(%q) did not return an error", encoded)
+		}
+	}
+}
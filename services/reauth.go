@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"technical-test/priverion/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ClaimsContextKey is the gin.Context key under which the JWT auth
+// middleware stores the caller's parsed *models.Claims.
+const ClaimsContextKey = "claims"
+
+// ReauthRequiredCode is the machine-readable error code returned alongside a
+// 401 when a sensitive operation is attempted without a fresh step-up claim.
+const ReauthRequiredCode = "reauth_required"
+
+// stepUpTTL is how long a successful reauthentication stays valid before the
+// caller must prove their password again.
+const stepUpTTL = 5 * time.Minute
+
+// stepUpCache tracks, per username, the deadline until which a sensitive
+// operation may proceed without asking for the password again.
+type stepUpCache struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+func newStepUpCache() *stepUpCache {
+	return &stepUpCache{expires: make(map[string]time.Time)}
+}
+
+func (c *stepUpCache) Mark(username string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expires[username] = time.Now().Add(stepUpTTL)
+}
+
+func (c *stepUpCache) Valid(username string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiresAt, ok := c.expires[username]
+	if !ok {
+		return false
+	}
+	if time.Now().After(expiresAt) {
+		delete(c.expires, username)
+		return false
+	}
+	return true
+}
+
+// claimsFromContext returns the authenticated caller's claims, as stored by
+// the JWT auth middleware under ClaimsContextKey.
+func claimsFromContext(ctx *gin.Context) (*models.Claims, error) {
+	raw, exists := ctx.Get(ClaimsContextKey)
+	if !exists {
+		return nil, fmt.Errorf("no authenticated user on request context")
+	}
+	claims, ok := raw.(*models.Claims)
+	if !ok {
+		return nil, fmt.Errorf("unexpected claims type on request context")
+	}
+	return claims, nil
+}
+
+// ReauthenticatePassword verifies the caller's current password (from a JSON
+// body of the form {"password": "..."}) against the authenticated user on
+// the request context, and on success grants a short-lived step-up window
+// during which RequireReauth will let sensitive requests through.
+func (us *UserService) ReauthenticatePassword(ctx *gin.Context) error {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		return err
+	}
+
+	var foundUser models.User
+	if err := us.collection.FindOne(context.TODO(), bson.M{"username": claims.Username}).Decode(&foundUser); err != nil {
+		return err
+	}
+
+	if !verifyPassword(body.Password, foundUser.Password) {
+		return fmt.Errorf("incorrect password")
+	}
+
+	us.stepUp.Mark(claims.Username)
+	return nil
+}
+
+// RequireReauth is Gin middleware that guards sensitive mutations (role
+// changes, account deletion, email/password changes, ...) behind a recent
+// call to ReauthenticatePassword. It aborts with 401 and a reauth_required
+// error code when the caller has no live step-up window.
+func (us *UserService) RequireReauth() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, err := claimsFromContext(ctx)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+				"code":  ReauthRequiredCode,
+			})
+			return
+		}
+
+		if !us.stepUp.Valid(claims.Username) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "this operation requires a recent password reauthentication",
+				"code":  ReauthRequiredCode,
+			})
+			return
+		}
+
+		ctx.Next()
+	}
+}
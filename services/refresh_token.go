@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"technical-test/priverion/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// refreshTokenTTL is how long a refresh token stays valid after issuance.
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+// refreshToken is the document stored in the refresh_tokens collection.
+type refreshToken struct {
+	ID         primitive.ObjectID  `bson:"_id"`
+	UserID     primitive.ObjectID  `bson:"user_id"`
+	TokenHash  string              `bson:"token_hash"`
+	IssuedAt   time.Time           `bson:"issued_at"`
+	ExpiresAt  time.Time           `bson:"expires_at"`
+	RevokedAt  *time.Time          `bson:"revoked_at"`
+	ReplacedBy *primitive.ObjectID `bson:"replaced_by"`
+	UserAgent  string              `bson:"user_agent"`
+	IP         string              `bson:"ip"`
+}
+
+// TokenPair bundles the short-lived access token with the opaque refresh
+// token returned to the client on login and on every refresh.
+type TokenPair struct {
+	Access         models.JWTOutput `json:"access"`
+	RefreshToken   string           `json:"refresh_token"`
+	RefreshExpires time.Time        `json:"refresh_token_expires"`
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// errReusedRefreshToken and errExpiredRefreshToken are returned by
+// checkRefreshToken so RefreshToken can tell a compromise signal (reuse)
+// apart from ordinary expiry.
+var (
+	errReusedRefreshToken  = fmt.Errorf("refresh token reuse detected, all sessions revoked")
+	errExpiredRefreshToken = fmt.Errorf("refresh token expired")
+)
+
+// checkRefreshToken validates a stored refresh token document against now,
+// independent of any database access, so rotation/reuse-detection logic can
+// be unit tested without a Mongo connection.
+func checkRefreshToken(stored refreshToken, now time.Time) error {
+	if stored.RevokedAt != nil {
+		// This token was already rotated or revoked: someone is replaying an
+		// old refresh token, so treat the whole chain as compromised.
+		return errReusedRefreshToken
+	}
+	if now.After(stored.ExpiresAt) {
+		return errExpiredRefreshToken
+	}
+	return nil
+}
+
+// newOpaqueToken generates a high-entropy, URL-safe refresh token.
+func newOpaqueToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// issueRefreshToken creates and persists a new refresh token for user, returning
+// the plaintext token handed to the client alongside the stored document's id
+// and expiry so callers can chain rotations without a second lookup.
+func (us *UserService) issueRefreshToken(ctx *gin.Context, userID primitive.ObjectID) (string, primitive.ObjectID, time.Time, error) {
+	plain, err := newOpaqueToken()
+	if err != nil {
+		return "", primitive.ObjectID{}, time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(refreshTokenTTL)
+	doc := refreshToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		TokenHash: hashRefreshToken(plain),
+		IssuedAt:  now,
+		ExpiresAt: expiresAt,
+		UserAgent: ctx.GetHeader("User-Agent"),
+		IP:        ctx.ClientIP(),
+	}
+
+	if _, err := us.refreshTokens.InsertOne(context.TODO(), doc); err != nil {
+		return "", primitive.ObjectID{}, time.Time{}, err
+	}
+
+	return plain, doc.ID, expiresAt, nil
+}
+
+// LogIn verifies the user's credentials and returns a fresh access/refresh
+// token pair. The access token remains a 60-minute JWT; the refresh token is
+// an opaque, long-lived value that can be exchanged via RefreshToken.
+func (us *UserService) LogIn(ctx *gin.Context) (TokenPair, error) {
+	var user models.User
+	var foundUser models.User
+	if err := ctx.ShouldBindJSON(&user); err != nil {
+		return TokenPair{}, err
+	}
+
+	if locked, unlockAt := us.IsLocked(user.Email); locked {
+		return TokenPair{}, &LockedError{RetryAfter: int(time.Until(unlockAt).Seconds()), UnlockAt: unlockAt}
+	}
+
+	// Verify email
+	err := us.collection.FindOne(context.TODO(), bson.M{"email": user.Email}).Decode(&foundUser)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			// User with the provided email not found; still record the
+			// failure so enumeration attempts contribute to the lockout.
+			if _, recErr := us.recordFailedLogin(user.Email, ctx.ClientIP()); recErr != nil {
+				return TokenPair{}, recErr
+			}
+			return TokenPair{}, fmt.Errorf("user not found")
+		}
+		return TokenPair{}, err
+	}
+
+	// Verify password
+	passwordIsValid, needsRehash, err := passwordHasher.Verify(user.Password, foundUser.Password)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if !passwordIsValid {
+		// Incorrect password
+		lockedErr, recErr := us.recordFailedLogin(user.Email, ctx.ClientIP())
+		if recErr != nil {
+			return TokenPair{}, recErr
+		}
+		if lockedErr != nil {
+			return TokenPair{}, lockedErr
+		}
+		return TokenPair{}, fmt.Errorf("incorrect password")
+	}
+
+	if err := us.ResetAttempts(user.Email); err != nil {
+		return TokenPair{}, err
+	}
+
+	if needsRehash {
+		// The stored hash predates the current algorithm/cost parameters:
+		// upgrade it transparently now that we have the plaintext in hand.
+		if rehashed, err := hashPassword(user.Password); err == nil {
+			_, _ = us.collection.UpdateOne(context.TODO(),
+				bson.M{"_id": foundUser.ID},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "password", Value: rehashed}}}},
+			)
+		}
+	}
+
+	access, errJWT := us.GenerateJWT(foundUser)
+	if errJWT != nil {
+		return TokenPair{}, errJWT
+	}
+
+	refresh, _, refreshExpiresAt, err := us.issueRefreshToken(ctx, foundUser.ID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		Access:         access,
+		RefreshToken:   refresh,
+		RefreshExpires: refreshExpiresAt,
+	}, nil
+}
+
+// RefreshToken exchanges a valid, unrevoked refresh token (read from the
+// request body as {"refresh_token": "..."}) for a new access token, rotating
+// the refresh token in the process. Reuse of an already-rotated token is
+// treated as a compromise signal and revokes the whole chain for that user.
+func (us *UserService) RefreshToken(ctx *gin.Context) (TokenPair, error) {
+	var body struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		return TokenPair{}, err
+	}
+
+	var stored refreshToken
+	tokenHash := hashRefreshToken(body.RefreshToken)
+	err := us.refreshTokens.FindOne(context.TODO(), bson.M{"token_hash": tokenHash}).Decode(&stored)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return TokenPair{}, fmt.Errorf("invalid refresh token")
+		}
+		return TokenPair{}, err
+	}
+
+	if err := checkRefreshToken(stored, time.Now()); err != nil {
+		if err == errReusedRefreshToken {
+			if revokeErr := us.RevokeAllForUser(stored.UserID); revokeErr != nil {
+				return TokenPair{}, revokeErr
+			}
+		}
+		return TokenPair{}, err
+	}
+
+	var foundUser models.User
+	if err := us.collection.FindOne(context.TODO(), bson.M{"_id": stored.UserID}).Decode(&foundUser); err != nil {
+		return TokenPair{}, err
+	}
+
+	newPlain, newID, newExpiresAt, err := us.issueRefreshToken(ctx, stored.UserID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	now := time.Now()
+	_, err = us.refreshTokens.UpdateOne(context.TODO(),
+		bson.M{"_id": stored.ID},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "revoked_at", Value: now},
+			{Key: "replaced_by", Value: newID},
+		}}},
+	)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	access, err := us.GenerateJWT(foundUser)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		Access:         access,
+		RefreshToken:   newPlain,
+		RefreshExpires: newExpiresAt,
+	}, nil
+}
+
+// Logout revokes the refresh token supplied in the request body so it can no
+// longer be exchanged for an access token.
+func (us *UserService) Logout(ctx *gin.Context) error {
+	var body struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	result, err := us.refreshTokens.UpdateOne(context.TODO(),
+		bson.M{"token_hash": hashRefreshToken(body.RefreshToken), "revoked_at": nil},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "revoked_at", Value: now}}}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return fmt.Errorf("refresh token not found or already revoked")
+	}
+	return nil
+}
+
+// RevokeAllForUser revokes every active refresh token belonging to userID,
+// e.g. in response to a detected token-reuse compromise or a manual
+// "log out everywhere" request.
+func (us *UserService) RevokeAllForUser(userID primitive.ObjectID) error {
+	now := time.Now()
+	_, err := us.refreshTokens.UpdateMany(context.TODO(),
+		bson.M{"user_id": userID, "revoked_at": nil},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "revoked_at", Value: now}}}},
+	)
+	if err != nil {
+		return fmt.Errorf("could not revoke refresh tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestHashRefreshToken_DeterministicAndDistinct(t *testing.T) {
+	a := hashRefreshToken("token-a")
+	b := hashRefreshToken("token-a")
+	c := hashRefreshToken("token-b")
+
+	if a != b {
+		t.Fatal("hashRefreshToken is not deterministic for the same input")
+	}
+	if a == c {
+		t.Fatal("hashRefreshToken produced the same hash for different inputs")
+	}
+}
+
+func TestNewOpaqueToken_UniqueAndHighEntropy(t *testing.T) {
+	a, err := newOpaqueToken()
+	if err != nil {
+		t.Fatalf("newOpaqueToken returned error: %v", err)
+	}
+	b, err := newOpaqueToken()
+	if err != nil {
+		t.Fatalf("newOpaqueToken returned error: %v", err)
+	}
+
+	if a == b {
+		t.Fatal("newOpaqueToken produced the same token twice")
+	}
+	if len(a) != 64 { // 32 random bytes, hex-encoded
+		t.Fatalf("expected a 64-character token, got %d characters", len(a))
+	}
+}
+
+func TestCheckRefreshToken_ValidToken(t *testing.T) {
+	now := time.Now()
+	stored := refreshToken{
+		ExpiresAt: now.Add(time.Hour),
+	}
+
+	if err := checkRefreshToken(stored, now); err != nil {
+		t.Fatalf("expected a valid, unexpired token to pass, got: %v", err)
+	}
+}
+
+func TestCheckRefreshToken_DetectsReuse(t *testing.T) {
+	now := time.Now()
+	revokedAt := now.Add(-time.Minute)
+	stored := refreshToken{
+		ExpiresAt: now.Add(time.Hour),
+		RevokedAt: &revokedAt,
+	}
+
+	err := checkRefreshToken(stored, now)
+	if err != errReusedRefreshToken {
+		t.Fatalf("expected errReusedRefreshToken for an already-revoked token, got: %v", err)
+	}
+}
+
+func TestCheckRefreshToken_DetectsExpiry(t *testing.T) {
+	now := time.Now()
+	stored := refreshToken{
+		ExpiresAt: now.Add(-time.Minute),
+	}
+
+	err := checkRefreshToken(stored, now)
+	if err != errExpiredRefreshToken {
+		t.Fatalf("expected errExpiredRefreshToken for a past-expiry token, got: %v", err)
+	}
+}
+
+func TestCheckRefreshToken_RevokedTakesPriorityOverExpiry(t *testing.T) {
+	now := time.Now()
+	revokedAt := now.Add(-time.Hour)
+	stored := refreshToken{
+		ExpiresAt: now.Add(-time.Minute), // also expired
+		RevokedAt: &revokedAt,
+	}
+
+	err := checkRefreshToken(stored, now)
+	if err != errReusedRefreshToken {
+		t.Fatalf("expected reuse to be reported even when the token is also expired, got: %v", err)
+	}
+}
+
+func TestCheckRefreshToken_IgnoresUnrelatedFields(t *testing.T) {
+	now := time.Now()
+	stored := refreshToken{
+		UserID:    primitive.NewObjectID(),
+		ExpiresAt: now.Add(time.Hour),
+	}
+
+	if err := checkRefreshToken(stored, now); err != nil {
+		t.Fatalf("expected UserID to be irrelevant to validity, got: %v", err)
+	}
+}
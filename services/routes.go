@@ -0,0 +1,44 @@
+package services
+
+import (
+	"net/http"
+
+	"technical-test/priverion/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes mounts the UserService endpoints that need route-level
+// middleware wiring rather than being left to the caller. Both routes sit
+// behind RequireAuth so claimsFromContext has an authenticated caller to
+// read; UpdateRoleUser (and any other destructive endpoint added later) is
+// additionally guarded by RequireReauth so a stolen access token alone can't
+// push through a role change without a recent password reauthentication.
+func RegisterRoutes(router gin.IRouter, us *UserService) {
+	router.POST("/reauth", RequireAuth(), us.reauthHandler)
+	router.PATCH("/users/:id/role", RequireAuth(), us.RequireReauth(), us.updateRoleHandler)
+}
+
+func (us *UserService) reauthHandler(ctx *gin.Context) {
+	if err := us.ReauthenticatePassword(ctx); err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "code": ReauthRequiredCode})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "reauthenticated"})
+}
+
+func (us *UserService) updateRoleHandler(ctx *gin.Context) {
+	var userUpdated models.User
+	if err := ctx.ShouldBindJSON(&userUpdated); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	modifiedCount, err := us.UpdateRoleUser(ctx.Param("id"), userUpdated)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"modified": modifiedCount})
+}
@@ -14,18 +14,34 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService struct {
-	db         *utils.Database
-	collection *mongo.Collection
+	db             *utils.Database
+	collection     *mongo.Collection
+	refreshTokens  *mongo.Collection
+	loginAttempts  *mongo.Collection
+	oauthBootstrap *mongo.Collection
+
+	providers         map[string]AuthProvider
+	stateStore        StateStore
+	defaultOAuthRoles []string
+
+	stepUp *stepUpCache
 }
 
 func NewUserService(db *utils.Database, dbName string, col string) *UserService {
+	database := db.Client.Database(dbName)
 	return &UserService{
-		db:         db,
-		collection: db.Client.Database(dbName).Collection(col),
+		db:                db,
+		collection:        database.Collection(col),
+		refreshTokens:     database.Collection("refresh_tokens"),
+		loginAttempts:     database.Collection("login_attempts"),
+		oauthBootstrap:    database.Collection("oauth_bootstrap"),
+		providers:         make(map[string]AuthProvider),
+		stateStore:        newMemoryStateStore(10 * time.Minute),
+		defaultOAuthRoles: []string{"user"},
+		stepUp:            newStepUpCache(),
 	}
 }
 
@@ -64,39 +80,11 @@ func (us *UserService) SignUp(ctx *gin.Context) (models.User, error) {
 	return user, nil
 }
 
-func (us *UserService) LogIn(ctx *gin.Context) (models.JWTOutput, error) {
-	var user models.User
-	var foundUser models.User
-	if err := ctx.ShouldBindJSON(&user); err != nil {
-		return models.JWTOutput{}, err
-	}
-	// Verify email
-	err := us.collection.FindOne(context.TODO(), bson.M{"email": user.Email}).Decode(&foundUser)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			// User with the provided email not found
-			return models.JWTOutput{}, fmt.Errorf("user not found")
-		}
-		return models.JWTOutput{}, err
-	}
-
-	// Verify password
-	passwordIsValid := verifyPassword(user.Password, foundUser.Password)
-	if !passwordIsValid {
-		// Incorrect password
-		return models.JWTOutput{}, fmt.Errorf("incorrect password")
-	}
-
-	token, errJWT := us.GenerateJWT(foundUser)
-	// Check for JWT token generation errors
-	if errJWT != nil {
-		return models.JWTOutput{}, errJWT
-	}
-
-	return token, nil
-}
-
 // update user role by id
+//
+// This is a sensitive mutation; RegisterRoutes mounts it behind
+// UserService.RequireReauth so a stolen access token alone can't escalate
+// privileges without a recent password reauthentication.
 func (us *UserService) UpdateRoleUser(id string, userUpdated models.User) (int, error) {
 	objectID, errParse := primitive.ObjectIDFromHex(id)
 	if errParse != nil {
@@ -151,20 +139,16 @@ func (us *UserService) GenerateJWT(user models.User) (models.JWTOutput, error) {
 }
 
 func hashPassword(password string) (string, error) {
-	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(password), 14)
-	if err != nil {
-		return "", err
-	}
-	return string(hashedPwd), nil
+	return passwordHasher.Hash(password)
 }
 
+// verifyPassword reports whether userPassword (plaintext) matches
+// providedPassword (the encoded hash on file), dispatching to whichever
+// PasswordHasher produced it.
 func verifyPassword(userPassword string, providedPassword string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(providedPassword), []byte(userPassword))
-	check := true
-
+	ok, _, err := passwordHasher.Verify(userPassword, providedPassword)
 	if err != nil {
-		check = false
+		return false
 	}
-
-	return check
+	return ok
 }